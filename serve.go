@@ -1,11 +1,33 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"html/template"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -31,24 +53,84 @@ DEPENDENCIES
     None... not even libc!
 
 ENVIRONMENT VARIABLES
+    AUTH_CONFIG
+        Path to a JSON file of per-path access rules, formatted as a list of
+        objects: '[{"prefix": "/private", "users": ["alice"]}]'. A request
+        whose path starts with 'prefix' is only allowed through for one of
+        the listed 'users'. Rules are checked in order and only the first
+        rule whose prefix matches applies; later matching rules are ignored.
+        Paths not covered by any rule are reachable by any authenticated
+        user. Only takes effect when AUTH_USER or AUTH_HTPASSWD is also set.
+    AUTH_HTPASSWD
+        Path to an htpasswd file (bcrypt or apr1/MD5 entries) used to
+        authenticate requests with HTTP Basic Auth. Mutually exclusive with
+        AUTH_USER/AUTH_PASS.
+    AUTH_PASS
+        The password required alongside AUTH_USER to authenticate requests
+        with HTTP Basic Auth. Must be supplied if AUTH_USER is supplied.
+    AUTH_USER
+        The username required to authenticate requests with HTTP Basic Auth.
+        Must be supplied if AUTH_PASS is supplied. Mutually exclusive with
+        AUTH_HTPASSWD. If neither this nor AUTH_HTPASSWD is supplied, no
+        authentication is performed.
     FOLDER
         The path to the folder containing the contents to be served over
         HTTP(s). If not supplied, defaults to '/web' (for Docker reasons).
     HOST
         The hostname used for binding. If not supplied, contents will be served
         to a client without regard for the hostname.
+    IDLE_TIMEOUT
+        How long to keep idle keep-alive connections open, as a Go duration
+        (e.g. '120s'). A value of '0' disables the timeout. Default '120s'.
+    LISTING
+        Controls how directory requests are handled. One of 'index' (serve
+        the directory's 'index.html', otherwise 'NOT FOUND'), 'auto' (serve
+        'index.html' if present, otherwise generate an HTML directory
+        listing) or 'none' (always 'NOT FOUND' for a directory request). If
+        not supplied, falls back to the value of SHOW_LISTING.
+    LISTING_TEMPLATE
+        Path to a Go html/template file used to render the 'auto' directory
+        listing. The template is passed a '.Path', a '.Parent' (true when a
+        parent-directory link should be shown) and '.Entries' (each with
+        '.Name', '.Size' and '.ModTime'). If not supplied, a built-in
+        template is used.
+    MAX_HEADER_BYTES
+        The maximum size, in bytes, of the request header. Default is the
+        value of Go's http.DefaultMaxHeaderBytes.
     PORT
         The port used for binding. If not supplied, defaults to port '8080'.
+    READ_HEADER_TIMEOUT
+        How long to allow for reading request headers, as a Go duration
+        (e.g. '10s'). A value of '0' disables the timeout. Default '10s'.
+    READ_TIMEOUT
+        How long to allow for reading the entire request, as a Go duration.
+        A value of '0' disables the timeout. Default '0' (disabled).
     SHOW_LISTING
         Automatically serve the index file for the directory if requested. For
         example, if the client requests 'http://127.0.0.1/' the 'index.html'
         file in the root of the directory being served is returned. If the value
         is set to 'false', the same request will return a 'NOT FOUND'. Default
-        value is 'true'.
+        value is 'true'. Superseded by LISTING when LISTING is also set.
+    SHUTDOWN_TIMEOUT
+        How long to wait for in-flight requests to finish draining after a
+        SIGINT or SIGTERM is received, as a Go duration, before the process
+        exits. Default '15s'.
     TLS_CERT
         Path to the TLS certificate file to serve files using HTTPS. If supplied
         then TLS_KEY must also be supplied. If not supplied, contents will be
         served via HTTP.
+    TLS_CLIENT_AUTH
+        Controls whether clients must present a certificate before files are
+        served. One of 'none', 'request', 'require', 'verify' or
+        'require-and-verify'. Only takes effect when TLS_CLIENT_CA is also
+        supplied. Defaults to 'require-and-verify' once TLS_CLIENT_CA is set,
+        otherwise 'none'.
+    TLS_CLIENT_CA
+        Path to a PEM file, or a directory of PEM files, containing the
+        certificate authorities trusted to sign client certificates. When
+        supplied, the server requires HTTPS (TLS_CERT/TLS_KEY) and verifies
+        incoming client certificates against this pool before serving any
+        file.
     TLS_KEY
         Path to the TLS key file to serve files using HTTPS. If supplied then
         TLS_CERT must also be supplied. If not supplied, contents will be served
@@ -57,6 +139,28 @@ ENVIRONMENT VARIABLES
         The prefix to use in the URL path. If supplied, then the prefix must
         start with a forward-slash and NOT end with a forward-slash. If not
         supplied then no prefix is used.
+    WEBHOOK_CMD
+        The command to run, via 'sh -c', when a valid request is received at
+        WEBHOOK_PATH. The request body is piped to the command's stdin, and
+        any top-level string fields of a JSON request body are also exported
+        as 'WEBHOOK_<FIELD>' environment variables (field names upper-cased).
+        Required if WEBHOOK_PATH is supplied.
+    WEBHOOK_PATH
+        The URL path, e.g. '/webhook', that triggers WEBHOOK_CMD. Requests
+        must use 'POST' and carry a valid 'X-Webhook-Signature-256' header
+        (see WEBHOOK_SECRET). Must not be '/', which is already registered
+        to serve files. If not supplied, no webhook endpoint is registered.
+    WEBHOOK_SECRET
+        The shared secret used to verify the 'X-Webhook-Signature-256'
+        header: 'sha256=' followed by the hex-encoded HMAC-SHA256 of the
+        request body, keyed with this value. Required if WEBHOOK_PATH is
+        supplied.
+    WEBHOOK_TIMEOUT
+        How long to let WEBHOOK_CMD run, as a Go duration, before it is
+        killed. Default '30s'.
+    WRITE_TIMEOUT
+        How long to allow for writing the response, as a Go duration. A
+        value of '0' disables the timeout. Default '0' (disabled).
 
 USAGE
     FILE LAYOUT
@@ -126,13 +230,31 @@ func main() {
 	}
 
 	// Collect environment variables.
+	authConfigPath := env("AUTH_CONFIG", "")
+	authHtpasswd := env("AUTH_HTPASSWD", "")
+	authPass := env("AUTH_PASS", "")
+	authUser := env("AUTH_USER", "")
 	folder := env("FOLDER", "/web") + "/"
 	host := env("HOST", "")
+	idleTimeout := envAsDuration("IDLE_TIMEOUT", 120*time.Second)
+	listingMode := env("LISTING", "")
+	listingTemplate := env("LISTING_TEMPLATE", "")
+	maxHeaderBytes := envAsInt("MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes)
 	port := env("PORT", "8080")
+	readHeaderTimeout := envAsDuration("READ_HEADER_TIMEOUT", 10*time.Second)
+	readTimeout := envAsDuration("READ_TIMEOUT", 0)
+	shutdownTimeout := envAsDuration("SHUTDOWN_TIMEOUT", 15*time.Second)
 	showListing := envAsBool("SHOW_LISTING", true)
 	tlsCert := env("TLS_CERT", "")
+	tlsClientAuth := env("TLS_CLIENT_AUTH", "")
+	tlsClientCA := env("TLS_CLIENT_CA", "")
 	tlsKey := env("TLS_KEY", "")
 	urlPrefix := env("URL_PREFIX", "")
+	webhookCmd := env("WEBHOOK_CMD", "")
+	webhookPath := env("WEBHOOK_PATH", "")
+	webhookSecret := env("WEBHOOK_SECRET", "")
+	webhookTimeout := envAsDuration("WEBHOOK_TIMEOUT", 30*time.Second)
+	writeTimeout := envAsDuration("WRITE_TIMEOUT", 0)
 
 	// If HTTPS is to be used, verify both TLS_* environment variables are set.
 	if 0 < len(tlsCert) || 0 < len(tlsKey) {
@@ -154,55 +276,701 @@ func main() {
 		)
 	}
 
+	// If a client CA pool is to be used, verify HTTPS is also configured.
+	if 0 < len(tlsClientCA) && 0 == len(tlsCert) {
+		log.Fatalln(
+			"Value for environment variable 'TLS_CLIENT_CA' requires " +
+				"'TLS_CERT' and 'TLS_KEY' to also be set.",
+		)
+	}
+
+	// If HTTP Basic Auth is to be used, verify the AUTH_* environment
+	// variables are consistent.
+	if (0 < len(authUser)) != (0 < len(authPass)) {
+		log.Fatalln(
+			"Value for environment variable 'AUTH_USER' or 'AUTH_PASS' is " +
+				"set without the other. Both must be supplied together.",
+		)
+	}
+	if 0 < len(authUser) && 0 < len(authHtpasswd) {
+		log.Fatalln(
+			"Environment variables 'AUTH_USER'/'AUTH_PASS' and " +
+				"'AUTH_HTPASSWD' are mutually exclusive.",
+		)
+	}
+
+	// If the webhook endpoint is to be used, verify it is fully configured and
+	// does not collide with the static file handler's own registration.
+	if 0 < len(webhookPath) && (0 == len(webhookSecret) || 0 == len(webhookCmd)) {
+		log.Fatalln(
+			"Value for environment variable 'WEBHOOK_PATH' requires " +
+				"'WEBHOOK_SECRET' and 'WEBHOOK_CMD' to also be set.",
+		)
+	}
+	if "/" == webhookPath {
+		log.Fatalln(
+			"Value for environment variable 'WEBHOOK_PATH' must not be '/', " +
+				"which is already registered to serve files.",
+		)
+	}
+
+	// Resolve the directory listing mode and, if supplied, its template.
+	mode, err := parseListingMode(listingMode, showListing)
+	if nil != err {
+		log.Fatalln(err)
+	}
+	tmpl, err := loadListingTemplate(listingTemplate)
+	if nil != err {
+		log.Fatalf("Failed to load 'LISTING_TEMPLATE': %v", err)
+	}
+	listing := listingConfig{mode: mode, tmpl: tmpl}
+
 	// Choose and set the appropriate, optimized static file serving function.
 	var handler http.HandlerFunc
 	if 0 == len(urlPrefix) {
-		handler = handleListing(showListing, basicHandler(folder))
+		handler = basicHandler(folder, listing)
 	} else {
-		handler = handleListing(showListing, prefixHandler(folder, urlPrefix))
+		handler = prefixHandler(folder, urlPrefix, listing)
+	}
+
+	// If HTTP Basic Auth is configured, wrap the handler so that no file is
+	// served until the client authenticates.
+	var creds *credentialStore
+	if 0 < len(authUser) {
+		creds = &credentialStore{user: authUser, pass: authPass}
+	} else if 0 < len(authHtpasswd) {
+		entries, err := loadHtpasswd(authHtpasswd)
+		if nil != err {
+			log.Fatalf("Failed to load 'AUTH_HTPASSWD': %v", err)
+		}
+		creds = &credentialStore{htpasswd: entries}
+	}
+	if nil != creds {
+		authRules, err := loadAuthConfig(authConfigPath)
+		if nil != err {
+			log.Fatalf("Failed to load 'AUTH_CONFIG': %v", err)
+		}
+		handler = authHandler(creds, authRules, handler)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handler)
+	if 0 < len(webhookPath) {
+		mux.HandleFunc(webhookPath, webhookHandler(webhookConfig{
+			secret:  webhookSecret,
+			cmd:     webhookCmd,
+			timeout: webhookTimeout,
+		}))
+	}
+
+	// Build an explicit server so timeouts, header limits and (for HTTPS)
+	// client certificate verification are under our control rather than the
+	// http package's defaults.
+	srv := &http.Server{
+		Addr:              host + ":" + port,
+		Handler:           mux,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	if 0 < len(tlsClientCA) {
+		clientAuth, err := parseClientAuthType(tlsClientAuth)
+		if nil != err {
+			log.Fatalln(err)
+		}
+		clientCAs, err := loadCertPool(tlsClientCA)
+		if nil != err {
+			log.Fatalf("Failed to load 'TLS_CLIENT_CA': %v", err)
+		}
+		srv.TLSConfig = &tls.Config{
+			ClientCAs:  clientCAs,
+			ClientAuth: clientAuth,
+			MinVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			},
+		}
+	}
+
+	// net/http enables HTTP/2 automatically for any *http.Server served over
+	// TLS (see ListenAndServeTLS), so no extra configuration is needed here.
+
+	// Serve files over HTTP or HTTPS, based on paths to TLS files being
+	// provided, until an interrupt or termination signal asks us to drain.
+	go func() {
+		var err error
+		if 0 == len(tlsCert) {
+			err = srv.ListenAndServe()
+		} else {
+			err = srv.ListenAndServeTLS(tlsCert, tlsKey)
+		}
+		if nil != err && http.ErrServerClosed != err {
+			log.Fatalln(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); nil != err {
+		log.Fatalln(err)
+	}
+}
+
+// parseClientAuthType maps the value of 'TLS_CLIENT_AUTH' to the
+// corresponding tls.ClientAuthType. An empty value defaults to
+// 'require-and-verify', since a client CA pool is only ever loaded when
+// client certificate verification has been requested.
+func parseClientAuthType(value string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(value) {
+	case "", "require-and-verify":
+		return tls.RequireAndVerifyClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify":
+		return tls.VerifyClientCertIfGiven, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf(
+			"Unknown value for 'TLS_CLIENT_AUTH': %s. Must be one of "+
+				"'none', 'request', 'require', 'verify' or "+
+				"'require-and-verify'.",
+			value,
+		)
+	}
+}
+
+// loadCertPool builds an x509.CertPool from the PEM file, or directory of PEM
+// files, located at path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	info, err := os.Stat(path)
+	if nil != err {
+		return nil, err
 	}
-	http.HandleFunc("/", handler)
 
-	// Serve files over HTTP or HTTPS based on paths to TLS files being provided.
-	if 0 == len(tlsCert) {
-		log.Fatalln(http.ListenAndServe(host+":"+port, nil))
+	var files []string
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(path)
+		if nil != err {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
 	} else {
-		log.Fatalln(http.ListenAndServeTLS(host+":"+port, tlsCert, tlsKey, nil))
+		files = append(files, path)
 	}
+
+	for _, file := range files {
+		pem, err := ioutil.ReadFile(file)
+		if nil != err {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in '%s'", file)
+		}
+	}
+
+	return pool, nil
 }
 
-// handleListing wraps an HTTP request. In the event of a folder root request,
-// setting 'show' to false will automatically return 'NOT FOUND' whereas true
-// will attempt to retrieve the index file of that directory.
-func handleListing(show bool, serve http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if show || strings.HasSuffix(r.URL.Path, "/") {
+// listingConfig controls how a directory request (a URL path ending in '/')
+// is resolved once no matching 'index.html' is found.
+type listingConfig struct {
+	mode string
+	tmpl *template.Template
+}
+
+// parseListingMode maps the value of 'LISTING' to one of 'index', 'auto' or
+// 'none'. An empty value falls back to the legacy 'SHOW_LISTING' boolean:
+// true becomes 'index', false becomes 'none'.
+func parseListingMode(value string, legacyShowListing bool) (string, error) {
+	if 0 == len(value) {
+		if legacyShowListing {
+			return "index", nil
+		}
+		return "none", nil
+	}
+	switch strings.ToLower(value) {
+	case "index", "auto", "none":
+		return strings.ToLower(value), nil
+	default:
+		return "", fmt.Errorf(
+			"Unknown value for 'LISTING': %s. Must be one of 'index', "+
+				"'auto' or 'none'.",
+			value,
+		)
+	}
+}
+
+// defaultListingTemplate renders a minimal, sorted directory autoindex with
+// file sizes, modification times and a parent-directory link.
+var defaultListingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<ul>
+{{if .Parent}}<li><a href="../">../</a></li>{{end}}
+{{range .Entries}}<li><a href="{{.Name}}">{{.Name}}</a> - {{.Size}} bytes - {{.ModTime}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// loadListingTemplate parses the template at path, or returns the built-in
+// template if path is empty.
+func loadListingTemplate(path string) (*template.Template, error) {
+	if 0 == len(path) {
+		return defaultListingTemplate, nil
+	}
+	return template.ParseFiles(path)
+}
+
+// listingEntry describes a single file or subdirectory rendered on an
+// autoindex page.
+type listingEntry struct {
+	Name    string
+	Size    int64
+	ModTime string
+}
+
+// listingPage is the data passed to the listing template.
+type listingPage struct {
+	Path    string
+	Parent  bool
+	Entries []listingEntry
+}
+
+// serveAutoindex renders an HTML directory listing for localPath, sorted by
+// name.
+func serveAutoindex(w http.ResponseWriter, r *http.Request, localPath string, tmpl *template.Template) {
+	files, err := ioutil.ReadDir(localPath)
+	if nil != err {
+		http.NotFound(w, r)
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	page := listingPage{Path: r.URL.Path, Parent: "/" != r.URL.Path}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			name += "/"
+		}
+		page.Entries = append(page.Entries, listingEntry{
+			Name:    name,
+			Size:    file.Size(),
+			ModTime: file.ModTime().Format(time.RFC1123),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, page); nil != err {
+		log.Printf("Failed to render listing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleListing serves localPath according to listing.mode. Plain files are
+// served directly. For a directory request with no 'index.html', mode 'auto'
+// renders a generated listing and mode 'none' or 'index' returns
+// 'NOT FOUND'.
+func handleListing(listing listingConfig, localPath string, w http.ResponseWriter, r *http.Request) {
+	isDirRequest := strings.HasSuffix(r.URL.Path, "/")
+
+	if isDirRequest {
+		if "none" == listing.mode {
 			http.NotFound(w, r)
 			return
 		}
-		serve(w, r)
+
+		// http.ServeFile serves a bare directory listing of its own when a
+		// directory has no 'index.html', so that case must be intercepted
+		// here for both 'index' (-> 'NOT FOUND') and 'auto' (-> autoindex).
+		if info, err := os.Stat(localPath); nil == err && info.IsDir() {
+			if _, err := os.Stat(filepath.Join(localPath, "index.html")); nil != err {
+				if "auto" == listing.mode {
+					serveAutoindex(w, r, localPath, listing.tmpl)
+					return
+				}
+				http.NotFound(w, r)
+				return
+			}
+		}
 	}
+
+	http.ServeFile(w, r, localPath)
 }
 
 // basicHandler serves files from the folder passed.
-func basicHandler(folder string) http.HandlerFunc {
+func basicHandler(folder string, listing listingConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, folder+r.URL.Path)
+		handleListing(listing, folder+r.URL.Path, w, r)
 	}
 }
 
 // prefixHandler removes the URL path prefix before serving files from the
 // folder passed.
-func prefixHandler(folder, urlPrefix string) http.HandlerFunc {
+func prefixHandler(folder, urlPrefix string, listing listingConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !strings.HasPrefix(r.URL.Path, urlPrefix) {
 			http.NotFound(w, r)
 			return
 		}
-		http.ServeFile(w, r, folder+strings.TrimPrefix(r.URL.Path, urlPrefix))
+		handleListing(listing, folder+strings.TrimPrefix(r.URL.Path, urlPrefix), w, r)
 	}
 }
 
+// credentialStore validates HTTP Basic Auth credentials against either a
+// single AUTH_USER/AUTH_PASS pair or an AUTH_HTPASSWD file (user -> hashed
+// password).
+type credentialStore struct {
+	user     string
+	pass     string
+	htpasswd map[string]string
+}
+
+// authenticate reports whether user/pass is a valid credential.
+func (c *credentialStore) authenticate(user, pass string) bool {
+	if 0 < len(c.user) {
+		userOK := 1 == subtle.ConstantTimeCompare([]byte(user), []byte(c.user))
+		passOK := 1 == subtle.ConstantTimeCompare([]byte(pass), []byte(c.pass))
+		return userOK && passOK
+	}
+	hash, ok := c.htpasswd[user]
+	if !ok {
+		return false
+	}
+	return verifyHtpasswd(hash, pass)
+}
+
+// loadHtpasswd parses an htpasswd file into a map of username to hashed
+// password, skipping blank lines and '#' comments.
+func loadHtpasswd(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if 0 == len(line) || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if 2 != len(parts) {
+			continue
+		}
+		entries[parts[0]] = parts[1]
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// verifyHtpasswd reports whether pass matches hash, an htpasswd entry in
+// bcrypt or apr1/MD5 format.
+func verifyHtpasswd(hash, pass string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return nil == bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass))
+	case strings.HasPrefix(hash, "$apr1$"):
+		parts := strings.SplitN(hash, "$", 4)
+		if 4 != len(parts) {
+			return false
+		}
+		computed := apr1Crypt(pass, parts[2])
+		return 1 == subtle.ConstantTimeCompare([]byte(computed), []byte(hash))
+	default:
+		return false
+	}
+}
+
+// apr1Crypt implements Apache's apr1 variant of MD5 crypt, returning a
+// '$apr1$salt$hash' formatted string.
+func apr1Crypt(password, salt string) string {
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	const magic = "$apr1$"
+
+	ctx := md5.New()
+	ctx.Write([]byte(password))
+	ctx.Write([]byte(magic))
+	ctx.Write([]byte(salt))
+
+	ctx1 := md5.New()
+	ctx1.Write([]byte(password))
+	ctx1.Write([]byte(salt))
+	ctx1.Write([]byte(password))
+	final := ctx1.Sum(nil)
+
+	for pl := len(password); 0 < pl; pl -= 16 {
+		if 16 < pl {
+			ctx.Write(final[:16])
+		} else {
+			ctx.Write(final[:pl])
+		}
+	}
+
+	for i := len(password); 0 != i; i >>= 1 {
+		if 0 != i&1 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write([]byte(password[:1]))
+		}
+	}
+	final = ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if 0 != i&1 {
+			round.Write([]byte(password))
+		} else {
+			round.Write(final[:16])
+		}
+		if 0 != i%3 {
+			round.Write([]byte(salt))
+		}
+		if 0 != i%7 {
+			round.Write([]byte(password))
+		}
+		if 0 != i&1 {
+			round.Write(final[:16])
+		} else {
+			round.Write([]byte(password))
+		}
+		final = round.Sum(nil)
+	}
+
+	var result bytes.Buffer
+	result.WriteString(magic)
+	result.WriteString(salt)
+	result.WriteString("$")
+
+	encode := func(a, b, c byte, n int) {
+		v := uint(a)<<16 | uint(b)<<8 | uint(c)
+		for i := 0; i < n; i++ {
+			result.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	encode(final[0], final[6], final[12], 4)
+	encode(final[1], final[7], final[13], 4)
+	encode(final[2], final[8], final[14], 4)
+	encode(final[3], final[9], final[15], 4)
+	encode(final[4], final[10], final[5], 4)
+	encode(0, 0, final[11], 2)
+
+	return result.String()
+}
+
+// pathRule restricts a URL prefix, via AUTH_CONFIG, to a set of authenticated
+// usernames.
+type pathRule struct {
+	Prefix string   `json:"prefix"`
+	Users  []string `json:"users"`
+}
+
+// loadAuthConfig parses the JSON file of path rules at path. An empty path
+// returns no rules, meaning any authenticated user may access any path.
+func loadAuthConfig(path string) ([]pathRule, error) {
+	if 0 == len(path) {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	var rules []pathRule
+	if err := json.Unmarshal(data, &rules); nil != err {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// authorized reports whether user may access urlPath under rules. A path not
+// covered by any rule is reachable by any authenticated user.
+func authorized(rules []pathRule, urlPath, user string) bool {
+	for _, rule := range rules {
+		if !strings.HasPrefix(urlPath, rule.Prefix) {
+			continue
+		}
+		for _, allowed := range rule.Users {
+			if allowed == user {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// authHandler wraps next in HTTP Basic Auth. A request must present a
+// credential known to creds and, per rules, be permitted for the requested
+// path.
+func authHandler(creds *credentialStore, rules []pathRule, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !creds.authenticate(user, pass) || !authorized(rules, r.URL.Path, user) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="static-file-server"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// maxWebhookBodyBytes bounds how much of a webhook request body is read into
+// memory before the HMAC signature can be checked.
+const maxWebhookBodyBytes = 10 << 20 // 10 MiB
+
+// webhookConfig configures the optional webhook trigger endpoint.
+type webhookConfig struct {
+	secret  string
+	cmd     string
+	timeout time.Duration
+}
+
+// webhookResult is the JSON response returned after running cfg.cmd.
+type webhookResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// webhookHandler verifies an HMAC-signed POST request and, on success, runs
+// cfg.cmd with the request body piped to its stdin.
+func webhookHandler(cfg webhookConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if http.MethodPost != r.Method {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+		if nil != err {
+			http.Error(w, "Payload Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		sig := r.Header.Get("X-Webhook-Signature-256")
+		if !verifyWebhookSignature(cfg.secret, body, sig) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", cfg.cmd)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Env = append(os.Environ(), webhookPayloadEnv(body)...)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		result := webhookResult{}
+		if err := cmd.Run(); nil != err {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				log.Printf("Failed to run 'WEBHOOK_CMD': %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			result.ExitCode = exitErr.ExitCode()
+		}
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); nil != err {
+			log.Printf("Failed to encode webhook response: %v", err)
+		}
+	}
+}
+
+// webhookPayloadEnv exports the top-level string fields of a JSON request
+// body as 'WEBHOOK_<FIELD>' environment variables. A body that is empty or
+// not a JSON object yields no variables.
+func webhookPayloadEnv(body []byte) []string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); nil != err {
+		return nil
+	}
+
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Fields whose upper-cased names collide (e.g. 'Foo' and 'foo') are
+	// resolved deterministically by this sorted order rather than map
+	// iteration order.
+	vars := map[string]string{}
+	for _, key := range keys {
+		if str, ok := payload[key].(string); ok {
+			vars[strings.ToUpper(key)] = str
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, fmt.Sprintf("WEBHOOK_%s=%s", name, vars[name]))
+	}
+	return env
+}
+
+// verifyWebhookSignature reports whether header is a valid
+// 'sha256=<hex HMAC-SHA256 of body, keyed with secret>' signature.
+func verifyWebhookSignature(secret string, body []byte, header string) bool {
+	const prefix = "sha256="
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+
+	sig, err := hex.DecodeString(strings.TrimPrefix(header, prefix))
+	if nil != err {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
 // env returns the value for an environment variable or, if not set, a fallback
 // value.
 func env(key, fallback string) string {
@@ -243,3 +1011,39 @@ func envAsBool(key string, fallback bool) bool {
 	}
 	return result
 }
+
+// envAsInt returns the value for an environment variable or, if not set or
+// not a valid integer, a fallback value as an int.
+func envAsInt(key string, fallback int) int {
+	value := env(key, strconv.Itoa(fallback))
+	result, err := strconv.Atoi(value)
+	if nil != err {
+		log.Printf(
+			"Invalid value for '%s': %v\nUsing fallback: %d",
+			key, err, fallback,
+		)
+		return fallback
+	}
+	return result
+}
+
+// envAsDuration returns the value for an environment variable, parsed with
+// time.ParseDuration (e.g. '30s', '2m'), or a fallback if not set or
+// invalid. A value of '0' returns a zero duration, which disables the
+// http.Server timeouts; SHUTDOWN_TIMEOUT and WEBHOOK_TIMEOUT treat it as an
+// immediate deadline instead.
+func envAsDuration(key string, fallback time.Duration) time.Duration {
+	value := env(key, fallback.String())
+	if "0" == value {
+		return 0
+	}
+	result, err := time.ParseDuration(value)
+	if nil != err {
+		log.Printf(
+			"Invalid value for '%s': %v\nUsing fallback: %s",
+			key, err, fallback,
+		)
+		return fallback
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := "s3cr3t"
+	body := []byte(`{"name":"world"}`)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	validSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	cases := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{"valid signature", validSig, true},
+		{"forged signature", "sha256=" + hex.EncodeToString(make([]byte, sha256.Size)), false},
+		{"wrong secret", func() string {
+			mac := hmac.New(sha256.New, []byte("wrong-secret"))
+			mac.Write(body)
+			return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}(), false},
+		{"missing prefix", hex.EncodeToString(mac.Sum(nil)), false},
+		{"not hex", "sha256=not-hex", false},
+		{"empty header", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := verifyWebhookSignature(secret, body, c.header); got != c.valid {
+				t.Fatalf("verifyWebhookSignature(header=%q) = %v, want %v", c.header, got, c.valid)
+			}
+		})
+	}
+}